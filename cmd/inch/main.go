@@ -81,14 +81,19 @@ func (m *Main) ParseFlags(args []string) error {
 	hosts := fs.String( "hosts", "http://localhost:8086", "Comma-separated list of hosts. Will be used in round-robin fashion")
 	fs.StringVar(&m.inch.User, "user", "", "Host User")
 	fs.StringVar(&m.inch.Password, "password", "", "Host Password")
-	fs.StringVar(&m.inch.Consistency, "consistency", "any", "Write consistency (default any)")
+	fs.StringVar(&m.inch.Consistency, "consistency", "any", "Write consistency (default any, ignored in v2 mode)")
+	fs.StringVar(&m.inch.APIVersion, "api-version", "v1", "InfluxDB write API to use: v1 or v2")
+	fs.StringVar(&m.inch.Token, "token", "", "API token (v2 only)")
+	fs.StringVar(&m.inch.Org, "org", "", "Organization (v2 only)")
+	fs.StringVar(&m.inch.Bucket, "bucket", "", "Bucket to write to (v2 only)")
 	fs.IntVar(&m.inch.Concurrency, "c", 1, "Concurrency")
 	fs.IntVar(&m.inch.Measurements, "m", 1, "Measurements")
 	tags := fs.String("t", "10,10,10", "Tag cardinality")
 	fs.IntVar(&m.inch.TagSize, "tag-size", -1, "Minimum size of tag value (default same as number of digits of value)")
 	fs.IntVar(&m.inch.PointsPerSeries, "p", 100, "Points per series")
-	fs.IntVar(&m.inch.FieldsPerPoint, "f", 1, "Fields per point")
-	fs.IntVar(&m.inch.FieldSize, "field-size", 1, "Size (in digits) of field value (default 1)")
+	fields := fs.String("f", "1", "Fields per point: a count, or name:type:params,... (e.g. temp:normal:20,3,cpu_pct:uniform:0,100)")
+	fs.IntVar(&m.inch.FieldSize, "field-size", 1, "Size (in digits) of field value (default 1); used only by workload-file scenarios that set fields_per_point, not by fields built from -f")
+	generator := fs.String("generator", "counter", "Default field value generator when -f is a plain count: counter, gauge, uniform, normal(mean,stddev), zipf(s,v), bool, string(len)")
 	fs.IntVar(&m.inch.BatchSize, "b", 5000, "Batch size")
 	fs.StringVar(&m.inch.Database, "db", "stress", "Database to write to")
 	fs.StringVar(&m.inch.ShardDuration, "shard-duration", "7d", "Set shard duration (default 7d)")
@@ -97,17 +102,39 @@ func (m *Main) ParseFlags(args []string) error {
 	fs.DurationVar(&m.inch.Delay, "delay", 0, "Delay between writes")
 	fs.IntVar(&m.inch.DatabaseCreationTimeout, "db-creation-timeout", 5, "Max time, in sec, to wait for database is created")
 	fs.DurationVar(&m.inch.TargetMaxLatency, "target-latency", 0, "If set inch will attempt to adapt write delay to meet target")
+	fs.IntVar(&m.inch.TargetPPS, "target-pps", 0, "If set inch will rate-limit writes to this many points/sec, backing off further if -target-latency is exceeded")
+	fs.IntVar(&m.inch.TargetBPS, "target-bps", 0, "If set inch will rate-limit writes to this many bytes/sec, backing off further if -target-latency is exceeded")
 	fs.StringVar(&m.cpuProfileFile, "cpuprofile", "", "write cpu profile to file")
+	fs.StringVar(&m.inch.ResultsFormat, "results-format", "", "Format for the final summary: json, csv or influx (default: human-readable only, or json when -results-file is set)")
+	fs.StringVar(&m.inch.ResultsFile, "results-file", "", "File to write the final summary to (default: stdout, when -results-format is set)")
+	fs.StringVar(&m.inch.PromListen, "prom-listen", "", "Address to serve live prometheus metrics on while the run is in progress, e.g. :9110")
+	config := fs.String("config", "", "Path to a TOML/YAML workload file describing one or more scenarios; overrides -m, -t, -f, etc.")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
+	if *config != "" {
+		scenarios, err := inch.LoadConfig(*config)
+		if err != nil {
+			return err
+		}
+		m.inch.Scenarios = scenarios
+	}
+
 	m.inch.Hosts = strings.Split(*hosts,",")
 	if len(m.inch.Hosts) == 0 {
 		return fmt.Errorf("cannot partse hosts: %s", *hosts)
 	}
 
+	// Parse field specs (a plain count, or name:type:params,... entries).
+	fieldSpecs, err := inch.ParseFieldSpecs(*fields, *generator)
+	if err != nil {
+		return fmt.Errorf("cannot parse fields: %s", err)
+	}
+	m.inch.FieldSpecs = fieldSpecs
+	m.inch.FieldsPerPoint = len(fieldSpecs)
+
 	// Parse tag cardinalities.
 	m.inch.Tags = []int{}
 	for _, s := range strings.Split(*tags, ",") {
@@ -0,0 +1,124 @@
+package inch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// workloadFile is the on-disk shape of a `-config` workload file: a list of
+// named scenarios, each of which may override any of Simulator's per-point
+// generation settings.
+type workloadFile struct {
+	Scenarios []scenarioConfig `toml:"scenario" yaml:"scenarios"`
+}
+
+// scenarioConfig mirrors Scenario but uses string/int fields that are
+// convenient to hand-write in TOML/YAML; durations are parsed with
+// time.ParseDuration (e.g. "500ms", "2s").
+type scenarioConfig struct {
+	Name           string `toml:"name" yaml:"name"`
+	Measurements   int    `toml:"measurements" yaml:"measurements"`
+	Tags           []int  `toml:"tags" yaml:"tags"`
+	FieldsPerPoint int    `toml:"fields_per_point" yaml:"fields_per_point"`
+	FieldSize      int    `toml:"field_size" yaml:"field_size"`
+	// Fields, when set, is parsed the same way as the CLI's -f flag (e.g.
+	// "temp:normal:20,3,load:uniform:0,1") and takes priority over
+	// FieldsPerPoint/FieldSize for this scenario.
+	Fields           string `toml:"fields" yaml:"fields"`
+	TagSize          int    `toml:"tag_size" yaml:"tag_size"`
+	PointsPerSeries  int    `toml:"points_per_series" yaml:"points_per_series"`
+	BatchSize        int    `toml:"batch_size" yaml:"batch_size"`
+	Delay            string `toml:"delay" yaml:"delay"`
+	TargetMaxLatency string `toml:"target_latency" yaml:"target_latency"`
+	TimeSpan         string `toml:"time_span" yaml:"time_span"`
+	Concurrent       bool   `toml:"concurrent" yaml:"concurrent"`
+}
+
+// LoadConfig reads a TOML or YAML workload file (format is chosen from the
+// file extension: .toml, or .yml/.yaml) and returns the scenarios it
+// describes. It is used by `-config` as an alternative to the flat
+// single-scenario CLI flags.
+func LoadConfig(path string) ([]Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read workload file: %v", err)
+	}
+
+	var wf workloadFile
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		if _, err := toml.Decode(string(data), &wf); err != nil {
+			return nil, fmt.Errorf("cannot parse workload file: %v", err)
+		}
+	case ".yml", ".yaml":
+		if err := yaml.Unmarshal(data, &wf); err != nil {
+			return nil, fmt.Errorf("cannot parse workload file: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized workload file extension %q (want .toml, .yml or .yaml)", ext)
+	}
+
+	if len(wf.Scenarios) == 0 {
+		return nil, fmt.Errorf("workload file %s defines no scenarios", path)
+	}
+
+	scenarios := make([]Scenario, len(wf.Scenarios))
+	for i, sc := range wf.Scenarios {
+		s, err := sc.toScenario()
+		if err != nil {
+			return nil, fmt.Errorf("scenario %d (%s): %v", i, sc.Name, err)
+		}
+		scenarios[i] = s
+	}
+	return scenarios, nil
+}
+
+// toScenario converts the on-disk representation into a Scenario, parsing
+// duration strings along the way.
+func (c scenarioConfig) toScenario() (Scenario, error) {
+	sc := Scenario{
+		Name:            c.Name,
+		Measurements:    c.Measurements,
+		Tags:            c.Tags,
+		FieldsPerPoint:  c.FieldsPerPoint,
+		FieldSize:       c.FieldSize,
+		TagSize:         c.TagSize,
+		PointsPerSeries: c.PointsPerSeries,
+		BatchSize:       c.BatchSize,
+		Concurrent:      c.Concurrent,
+	}
+
+	if c.Fields != "" {
+		specs, err := ParseFieldSpecs(c.Fields, "")
+		if err != nil {
+			return sc, fmt.Errorf("invalid fields: %v", err)
+		}
+		sc.FieldSpecs = specs
+	}
+
+	var err error
+	if sc.Delay, err = parseOptionalDuration(c.Delay); err != nil {
+		return sc, fmt.Errorf("invalid delay: %v", err)
+	}
+	if sc.TargetMaxLatency, err = parseOptionalDuration(c.TargetMaxLatency); err != nil {
+		return sc, fmt.Errorf("invalid target_latency: %v", err)
+	}
+	if sc.TimeSpan, err = parseOptionalDuration(c.TimeSpan); err != nil {
+		return sc, fmt.Errorf("invalid time_span: %v", err)
+	}
+	return sc, nil
+}
+
+// parseOptionalDuration parses s as a duration, returning 0 if s is empty.
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,102 @@
+package inch
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTempConfig(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing temp config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	path := writeTempConfig(t, "workload.toml", `
+[[scenario]]
+name = "metrics"
+measurements = 2
+fields_per_point = 5
+delay = "10ms"
+
+[[scenario]]
+name = "events"
+fields = "msg:string:16"
+concurrent = true
+`)
+
+	scenarios, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(scenarios) != 2 {
+		t.Fatalf("got %d scenarios, want 2", len(scenarios))
+	}
+
+	if got := scenarios[0].Name; got != "metrics" {
+		t.Errorf("scenarios[0].Name = %q, want %q", got, "metrics")
+	}
+	if got := scenarios[0].FieldsPerPoint; got != 5 {
+		t.Errorf("scenarios[0].FieldsPerPoint = %d, want 5", got)
+	}
+	if got := scenarios[0].Delay; got != 10*time.Millisecond {
+		t.Errorf("scenarios[0].Delay = %v, want 10ms", got)
+	}
+
+	if !scenarios[1].Concurrent {
+		t.Errorf("scenarios[1].Concurrent = false, want true")
+	}
+	if len(scenarios[1].FieldSpecs) != 1 || scenarios[1].FieldSpecs[0].Name != "msg" {
+		t.Errorf("scenarios[1].FieldSpecs = %+v, want one spec named %q", scenarios[1].FieldSpecs, "msg")
+	}
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	path := writeTempConfig(t, "workload.yaml", `
+scenarios:
+  - name: metrics
+    measurements: 3
+    points_per_series: 50
+`)
+
+	scenarios, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(scenarios) != 1 {
+		t.Fatalf("got %d scenarios, want 1", len(scenarios))
+	}
+	if got := scenarios[0].PointsPerSeries; got != 50 {
+		t.Errorf("scenarios[0].PointsPerSeries = %d, want 50", got)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	if _, err := LoadConfig("/does/not/exist.toml"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	emptyPath := writeTempConfig(t, "empty.toml", "")
+	if _, err := LoadConfig(emptyPath); err == nil {
+		t.Error("expected an error for a workload file with no scenarios")
+	}
+
+	unknownExtPath := writeTempConfig(t, "workload.ini", "")
+	if _, err := LoadConfig(unknownExtPath); err == nil {
+		t.Error("expected an error for an unrecognized file extension")
+	}
+
+	badDurationPath := writeTempConfig(t, "bad.toml", `
+[[scenario]]
+name = "metrics"
+delay = "not-a-duration"
+`)
+	if _, err := LoadConfig(badDurationPath); err == nil {
+		t.Error("expected an error for an invalid delay duration")
+	}
+}
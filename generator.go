@@ -0,0 +1,366 @@
+package inch
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// PointGenerator produces successive field values for a single column.
+// NextBatch appends n freshly generated, comma-free line-protocol value
+// tokens to buf (joined by commas) and returns the extended slice.
+type PointGenerator interface {
+	NextBatch(buf []byte, n int) []byte
+}
+
+// FieldSpec names one field in a point and the generator that supplies its
+// values. It is the parsed form of a "-f name:type:params" entry.
+type FieldSpec struct {
+	Name string
+	Gen  PointGenerator
+}
+
+// NewPointGenerator builds the PointGenerator named by typ, configured with
+// the params that followed it (e.g. typ "normal" with params ["20", "3"]
+// means mean=20, stddev=3). An empty typ defaults to "counter", a
+// monotonically increasing integer.
+func NewPointGenerator(typ string, params []string) (PointGenerator, error) {
+	switch typ {
+	case "", "counter":
+		return &counterGenerator{}, nil
+	case "gauge":
+		return newGaugeGenerator(params)
+	case "uniform":
+		return newUniformGenerator(params)
+	case "normal":
+		return newNormalGenerator(params)
+	case "zipf":
+		return newZipfGenerator(params)
+	case "bool":
+		return &boolGenerator{}, nil
+	case "string":
+		return newStringGenerator(params)
+	default:
+		return nil, fmt.Errorf("unknown generator type %q", typ)
+	}
+}
+
+// parseGeneratorType splits a "-generator" value of the form
+// "type(param,param,...)" into its type name and parameters, e.g.
+// "normal(20,3)" becomes ("normal", ["20", "3"]). A type with no
+// parentheses (e.g. "counter") is returned unchanged with a nil params slice.
+func parseGeneratorType(s string) (string, []string) {
+	open := strings.IndexByte(s, '(')
+	if open == -1 || !strings.HasSuffix(s, ")") {
+		return s, nil
+	}
+	typ, paramStr := s[:open], s[open+1:len(s)-1]
+	if paramStr == "" {
+		return typ, nil
+	}
+	return typ, strings.Split(paramStr, ",")
+}
+
+// ParseFieldSpecs parses a -f argument into a list of named field specs.
+//
+// Two forms are accepted:
+//
+//	-f 3                              three counter (or defaultType) fields, named f0..f2
+//	-f temp:normal:20,3,load:uniform:0,1   explicitly named and typed fields
+//
+// In the second form, specs are comma-separated but a spec's own params may
+// themselves contain commas, so a token only starts a new spec when it
+// contains a colon; a bare token continues the previous spec's param list.
+//
+// In the first form, defaultType may carry its own parameters in
+// "type(params,...)" form (e.g. "normal(20,3)"), matching the -generator
+// flag's documented syntax for generators that need them.
+func ParseFieldSpecs(s string, defaultType string) ([]FieldSpec, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		typ, params := parseGeneratorType(defaultType)
+		specs := make([]FieldSpec, n)
+		for i := range specs {
+			gen, err := NewPointGenerator(typ, params)
+			if err != nil {
+				return nil, err
+			}
+			specs[i] = FieldSpec{Name: fmt.Sprintf("f%d", i), Gen: gen}
+		}
+		return specs, nil
+	}
+
+	var specs []FieldSpec
+	var curName, curType string
+	var curParams []string
+
+	flush := func() error {
+		if curName == "" {
+			return nil
+		}
+		gen, err := NewPointGenerator(curType, curParams)
+		if err != nil {
+			return fmt.Errorf("field %q: %v", curName, err)
+		}
+		specs = append(specs, FieldSpec{Name: curName, Gen: gen})
+		return nil
+	}
+
+	for _, tok := range strings.Split(s, ",") {
+		parts := strings.SplitN(tok, ":", 3)
+		if len(parts) == 1 {
+			// Continuation of the previous spec's params.
+			if curName == "" {
+				return nil, fmt.Errorf("invalid field spec %q: no name:type preceding it", tok)
+			}
+			curParams = append(curParams, parts[0])
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return nil, err
+		}
+
+		curName, curType, curParams = parts[0], parts[1], nil
+		if len(parts) == 3 && parts[2] != "" {
+			curParams = append(curParams, parts[2])
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return specs, nil
+}
+
+// counterGenerator produces a monotonically increasing integer starting at
+// 1, shared across every value it's asked to produce. Unlike the legacy fN
+// fields it is not reset per series and not zero-padded to -field-size.
+type counterGenerator struct {
+	n int64
+}
+
+func (g *counterGenerator) NextBatch(buf []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		g.n++
+		buf = strconv.AppendInt(buf, g.n, 10)
+	}
+	return buf
+}
+
+// gaugeGenerator produces a bounded random walk, the shape of a typical
+// gauge metric (e.g. CPU percent, queue depth).
+type gaugeGenerator struct {
+	min, max, step, value float64
+}
+
+func newGaugeGenerator(params []string) (*gaugeGenerator, error) {
+	min, max := 0.0, 100.0
+	if len(params) > 0 {
+		var err error
+		if min, err = strconv.ParseFloat(params[0], 64); err != nil {
+			return nil, fmt.Errorf("gauge: invalid min: %v", err)
+		}
+	}
+	if len(params) > 1 {
+		var err error
+		if max, err = strconv.ParseFloat(params[1], 64); err != nil {
+			return nil, fmt.Errorf("gauge: invalid max: %v", err)
+		}
+	}
+	return &gaugeGenerator{min: min, max: max, step: (max - min) * 0.05, value: (min + max) / 2}, nil
+}
+
+func (g *gaugeGenerator) NextBatch(buf []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		g.value += (rand.Float64()*2 - 1) * g.step
+		if g.value < g.min {
+			g.value = g.min
+		}
+		if g.value > g.max {
+			g.value = g.max
+		}
+		buf = strconv.AppendFloat(buf, g.value, 'f', -1, 64)
+	}
+	return buf
+}
+
+// uniformGenerator produces values uniformly distributed over [min, max).
+type uniformGenerator struct {
+	min, max float64
+}
+
+func newUniformGenerator(params []string) (*uniformGenerator, error) {
+	min, max := 0.0, 1.0
+	if len(params) > 0 {
+		var err error
+		if min, err = strconv.ParseFloat(params[0], 64); err != nil {
+			return nil, fmt.Errorf("uniform: invalid min: %v", err)
+		}
+	}
+	if len(params) > 1 {
+		var err error
+		if max, err = strconv.ParseFloat(params[1], 64); err != nil {
+			return nil, fmt.Errorf("uniform: invalid max: %v", err)
+		}
+	}
+	return &uniformGenerator{min: min, max: max}, nil
+}
+
+func (g *uniformGenerator) NextBatch(buf []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		v := g.min + rand.Float64()*(g.max-g.min)
+		buf = strconv.AppendFloat(buf, v, 'f', -1, 64)
+	}
+	return buf
+}
+
+// normalGenerator produces values from a normal distribution with the given
+// mean and standard deviation.
+type normalGenerator struct {
+	mean, stddev float64
+}
+
+func newNormalGenerator(params []string) (*normalGenerator, error) {
+	if len(params) < 2 {
+		return nil, fmt.Errorf("normal: expected params mean,stddev")
+	}
+	mean, err := strconv.ParseFloat(params[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("normal: invalid mean: %v", err)
+	}
+	stddev, err := strconv.ParseFloat(params[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("normal: invalid stddev: %v", err)
+	}
+	return &normalGenerator{mean: mean, stddev: stddev}, nil
+}
+
+func (g *normalGenerator) NextBatch(buf []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		v := rand.NormFloat64()*g.stddev + g.mean
+		buf = strconv.AppendFloat(buf, v, 'f', -1, 64)
+	}
+	return buf
+}
+
+// zipfGenerator produces integer values following a Zipf distribution,
+// useful for simulating skewed cardinality (a handful of hot series IDs).
+type zipfGenerator struct {
+	z *rand.Zipf
+}
+
+func newZipfGenerator(params []string) (*zipfGenerator, error) {
+	if len(params) < 2 {
+		return nil, fmt.Errorf("zipf: expected params s,v")
+	}
+	s, err := strconv.ParseFloat(params[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("zipf: invalid s: %v", err)
+	}
+	v, err := strconv.ParseFloat(params[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("zipf: invalid v: %v", err)
+	}
+	imax := uint64(1e6)
+	if len(params) > 2 {
+		n, err := strconv.ParseUint(params[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("zipf: invalid imax: %v", err)
+		}
+		imax = n
+	}
+	z := rand.NewZipf(rand.New(rand.NewSource(rand.Int63())), s, v, imax)
+	if z == nil {
+		return nil, fmt.Errorf("zipf: s must be > 1 and v must be >= 1")
+	}
+	return &zipfGenerator{z: z}, nil
+}
+
+func (g *zipfGenerator) NextBatch(buf []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendUint(buf, g.z.Uint64(), 10)
+	}
+	return buf
+}
+
+// boolGenerator produces random booleans.
+type boolGenerator struct{}
+
+func (g *boolGenerator) NextBatch(buf []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		if rand.Intn(2) == 0 {
+			buf = append(buf, "false"...)
+		} else {
+			buf = append(buf, "true"...)
+		}
+	}
+	return buf
+}
+
+const stringGeneratorAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// stringGenerator produces random alphanumeric strings of a fixed length,
+// quoted and escaped as an InfluxDB line-protocol string field.
+type stringGenerator struct {
+	length int
+}
+
+func newStringGenerator(params []string) (*stringGenerator, error) {
+	length := 8
+	if len(params) > 0 {
+		n, err := strconv.Atoi(params[0])
+		if err != nil {
+			return nil, fmt.Errorf("string: invalid length: %v", err)
+		}
+		length = n
+	}
+	return &stringGenerator{length: length}, nil
+}
+
+func (g *stringGenerator) NextBatch(buf []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		b := make([]byte, g.length)
+		for j := range b {
+			b[j] = stringGeneratorAlphabet[rand.Intn(len(stringGeneratorAlphabet))]
+		}
+		buf = appendEscapedStringField(buf, string(b))
+	}
+	return buf
+}
+
+// appendEscapedStringField appends s to buf as a quoted line-protocol string
+// field value, backslash-escaping the characters that influxdb's `models`
+// line-protocol parser requires escaped inside a string field: the
+// surrounding quote character and backslash itself.
+func appendEscapedStringField(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, string(r)...)
+	}
+	return append(buf, '"')
+}
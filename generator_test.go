@@ -0,0 +1,102 @@
+package inch
+
+import (
+	"testing"
+)
+
+func TestParseGeneratorType(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantType   string
+		wantParams []string
+	}{
+		{"counter", "counter", nil},
+		{"normal(20,3)", "normal", []string{"20", "3"}},
+		{"zipf(1.5,1)", "zipf", []string{"1.5", "1"}},
+		{"string()", "string", nil},
+	}
+
+	for _, tt := range tests {
+		typ, params := parseGeneratorType(tt.in)
+		if typ != tt.wantType || !equalStrings(params, tt.wantParams) {
+			t.Errorf("parseGeneratorType(%q) = (%q, %v), want (%q, %v)", tt.in, typ, params, tt.wantType, tt.wantParams)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseFieldSpecsPlainCount(t *testing.T) {
+	specs, err := ParseFieldSpecs("3", "counter")
+	if err != nil {
+		t.Fatalf("ParseFieldSpecs: %v", err)
+	}
+	if len(specs) != 3 {
+		t.Fatalf("got %d specs, want 3", len(specs))
+	}
+	for i, spec := range specs {
+		wantName := "f" + string(rune('0'+i))
+		if spec.Name != wantName {
+			t.Errorf("spec[%d].Name = %q, want %q", i, spec.Name, wantName)
+		}
+	}
+}
+
+func TestParseFieldSpecsDefaultTypeWithParams(t *testing.T) {
+	// A -generator value with its own parameters (e.g. "normal(20,3)")
+	// must reach the underlying generator, not fail as if called bare.
+	specs, err := ParseFieldSpecs("2", "normal(20,3)")
+	if err != nil {
+		t.Fatalf("ParseFieldSpecs: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("got %d specs, want 2", len(specs))
+	}
+	if _, ok := specs[0].Gen.(*normalGenerator); !ok {
+		t.Fatalf("specs[0].Gen = %T, want *normalGenerator", specs[0].Gen)
+	}
+}
+
+func TestParseFieldSpecsNamed(t *testing.T) {
+	specs, err := ParseFieldSpecs("temp:normal:20,3,load:uniform:0,1", "")
+	if err != nil {
+		t.Fatalf("ParseFieldSpecs: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("got %d specs, want 2", len(specs))
+	}
+	if specs[0].Name != "temp" || specs[1].Name != "load" {
+		t.Fatalf("got names %q, %q, want temp, load", specs[0].Name, specs[1].Name)
+	}
+	if _, ok := specs[0].Gen.(*normalGenerator); !ok {
+		t.Errorf("specs[0].Gen = %T, want *normalGenerator", specs[0].Gen)
+	}
+	if _, ok := specs[1].Gen.(*uniformGenerator); !ok {
+		t.Errorf("specs[1].Gen = %T, want *uniformGenerator", specs[1].Gen)
+	}
+}
+
+func TestParseFieldSpecsInvalid(t *testing.T) {
+	if _, err := ParseFieldSpecs("bogus:unknowntype", ""); err == nil {
+		t.Fatal("expected an error for an unknown generator type")
+	}
+	if _, err := ParseFieldSpecs("a,b", ""); err == nil {
+		t.Fatal("expected an error for a bare continuation with no preceding name:type")
+	}
+}
+
+func TestNewPointGeneratorUnknownType(t *testing.T) {
+	if _, err := NewPointGenerator("bogus", nil); err == nil {
+		t.Fatal("expected an error for an unknown generator type")
+	}
+}
@@ -0,0 +1,76 @@
+package inch
+
+import (
+	"sync"
+	"time"
+
+	hdr "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// HDR histogram range: 1µs to 60s at 3 significant digits, matching the
+// range of latencies a single write can plausibly take.
+const (
+	hdrLowestMicros  = 1
+	hdrHighestMicros = 60 * 1000 * 1000
+	hdrSigFigs       = 3
+)
+
+func newLatencyHistogram() *hdr.Histogram {
+	return hdr.New(hdrLowestMicros, hdrHighestMicros, hdrSigFigs)
+}
+
+// latencyHistograms holds one writer goroutine's raw and coordinated-
+// omission-corrected latency samples. Each writer accumulates into its own
+// pair lock-free; only the one-time merge into the Simulator's totals at the
+// end of the goroutine's life takes a lock.
+type latencyHistograms struct {
+	raw *hdr.Histogram
+	co  *hdr.Histogram
+}
+
+func newLatencyHistograms() *latencyHistograms {
+	return &latencyHistograms{raw: newLatencyHistogram(), co: newLatencyHistogram()}
+}
+
+// record adds one write's latency to raw, and - when expectedInterval is
+// known (a target-pps/-bps/-latency run) - synthesizes the extra samples a
+// coordinated-omission-corrected histogram needs: if the write should have
+// taken `expectedInterval` but actually took `latency`, an ideal closed-loop
+// client would also have observed batches queued up behind it at
+// latency-expectedInterval, latency-2*expectedInterval, and so on.
+func (h *latencyHistograms) record(latency, expectedInterval time.Duration) {
+	micros := int64(latency / time.Microsecond)
+	if micros < hdrLowestMicros {
+		micros = hdrLowestMicros
+	}
+	h.raw.RecordValue(micros)
+
+	if expectedInterval <= 0 {
+		h.co.RecordValue(micros)
+		return
+	}
+	expectedMicros := int64(expectedInterval / time.Microsecond)
+	if expectedMicros < 1 {
+		expectedMicros = 1
+	}
+	h.co.RecordCorrectedValue(micros, expectedMicros)
+}
+
+// histogramMerger accumulates every writer goroutine's histograms into two
+// run-wide totals, merged once per goroutine rather than once per sample.
+type histogramMerger struct {
+	mu  sync.Mutex
+	raw *hdr.Histogram
+	co  *hdr.Histogram
+}
+
+func newHistogramMerger() *histogramMerger {
+	return &histogramMerger{raw: newLatencyHistogram(), co: newLatencyHistogram()}
+}
+
+func (m *histogramMerger) merge(h *latencyHistograms) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.raw.Merge(h.raw)
+	m.co.Merge(h.co)
+}
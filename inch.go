@@ -0,0 +1,923 @@
+// Package inch provides a tool for testing InfluxDB write and query performance.
+package inch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Scenario describes one named workload phase: its own measurement/tag/field
+// shape, batch size, delay and target latency. Scenarios let a single inch
+// run exercise several write patterns (e.g. a steady metrics writer plus a
+// bursty events writer) without spawning separate processes.
+//
+// Fields left at their zero value fall back to the equivalent Simulator
+// field, so a workload file only needs to specify what differs between
+// scenarios.
+type Scenario struct {
+	Name string
+
+	Measurements     int
+	Tags             []int
+	FieldsPerPoint   int
+	FieldSize        int
+	TagSize          int
+	PointsPerSeries  int
+	BatchSize        int
+	Delay            time.Duration
+	TargetMaxLatency time.Duration
+	TimeSpan         time.Duration
+
+	// FieldSpecs, when set, takes priority over FieldsPerPoint/FieldSize:
+	// each point is built from these named, independently-generated
+	// fields instead of the legacy fN=<padded integer> fields.
+	FieldSpecs []FieldSpec
+
+	// Concurrent, if true, runs this scenario alongside the other
+	// scenarios instead of waiting for the previous one to finish.
+	Concurrent bool
+}
+
+// Simulator represents the main program execution.
+type Simulator struct {
+	mu         sync.Mutex
+	writtenN   int
+	totalBytes int64
+	startTime  time.Time
+	baseTime   time.Time
+	now        time.Time
+	retryCount int
+
+	scenarioStats   map[string]*scenarioStats
+	scenarioStatsMu sync.Mutex
+
+	errorsByHost map[string]int
+	errorsMu     sync.Mutex
+
+	prom *promMetrics
+
+	histograms *histogramMerger
+
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Client used for writes and database creation.
+	httpClient *http.Client
+
+	Verbose        bool
+	ReportHost     string
+	ReportUser     string
+	ReportPassword string
+	ReportTags     map[string]string
+	DryRun         bool
+	MaxErrors      int
+
+	Hosts       []string
+	hostIdx     uint64
+	User        string
+	Password    string
+	Consistency string
+
+	// APIVersion selects the write protocol: "v1" (the default, basic-auth
+	// `/write?db=...`) or "v2" (token-auth `/api/v2/write?org=...&bucket=...`).
+	APIVersion string
+	Token      string
+	Org        string
+	Bucket     string
+
+	// Scenarios holds the named workloads to run. When empty, Run builds
+	// a single anonymous scenario from the flat fields below, preserving
+	// the traditional single-workload CLI behavior.
+	Scenarios []Scenario
+
+	Concurrency     int
+	Measurements    int
+	Tags            []int
+	TagSize         int
+	PointsPerSeries int
+	FieldsPerPoint  int
+	FieldSize       int
+
+	// FieldSpecs, when set, takes priority over FieldsPerPoint/FieldSize
+	// for scenarios that don't specify their own. See Scenario.FieldSpecs.
+	FieldSpecs []FieldSpec
+
+	BatchSize               int
+	Database                string
+	ShardDuration           string
+	StartTime               string
+	TimeSpan                time.Duration
+	Delay                   time.Duration
+	DatabaseCreationTimeout int
+	TargetMaxLatency        time.Duration
+
+	// TargetPPS and TargetBPS, if set, drive a closed-loop token-bucket
+	// rate controller shared by every writer instead of (or alongside)
+	// TargetMaxLatency's delay-based adaptation.
+	TargetPPS int
+	TargetBPS int
+
+	// ResultsFormat ("json", "csv" or "influx") and ResultsFile control
+	// where the final machine-readable summary goes; if ResultsFile is
+	// empty it is written to Stdout. PromListen, if set, serves live
+	// counters/histograms on that address for the duration of the run.
+	ResultsFormat string
+	ResultsFile   string
+	PromListen    string
+}
+
+// scenarioStats tracks the running totals for a single scenario so the final
+// report can break results down per phase.
+type scenarioStats struct {
+	writtenN int
+}
+
+// NewSimulator returns a new instance of Simulator with default settings.
+func NewSimulator() *Simulator {
+	return &Simulator{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+
+		httpClient: &http.Client{},
+
+		scenarioStats: make(map[string]*scenarioStats),
+		errorsByHost:  make(map[string]int),
+		histograms:    newHistogramMerger(),
+
+		Concurrency:             1,
+		Measurements:            1,
+		Tags:                    []int{10, 10, 10},
+		TagSize:                 -1,
+		PointsPerSeries:         100,
+		FieldsPerPoint:          1,
+		FieldSize:               1,
+		BatchSize:               5000,
+		Database:                "stress",
+		ShardDuration:           "7d",
+		Consistency:             "any",
+		APIVersion:              "v1",
+		DatabaseCreationTimeout: 5,
+	}
+}
+
+// Validate checks the simulator configuration before a run.
+func (s *Simulator) Validate() error {
+	if len(s.Hosts) == 0 {
+		return fmt.Errorf("at least one host must be provided")
+	}
+
+	switch {
+	case s.StartTime == "":
+		s.baseTime = time.Now().UTC()
+	default:
+		var err error
+		s.baseTime, err = time.Parse(time.RFC3339, s.StartTime)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %v", err)
+		}
+	}
+	s.now = s.baseTime
+
+	for i := range s.Scenarios {
+		if s.Scenarios[i].BatchSize == 0 {
+			s.Scenarios[i].BatchSize = s.BatchSize
+		}
+	}
+	if len(s.Scenarios) == 0 && s.BatchSize <= 0 {
+		return fmt.Errorf("batch size must be greater than 0")
+	}
+
+	switch s.APIVersion {
+	case "", "v1":
+		s.APIVersion = "v1"
+	case "v2":
+		if s.Token == "" {
+			return fmt.Errorf("-token is required in v2 mode")
+		}
+		if s.Org == "" {
+			return fmt.Errorf("-org is required in v2 mode")
+		}
+		if s.Bucket == "" {
+			return fmt.Errorf("-bucket is required in v2 mode")
+		}
+	default:
+		return fmt.Errorf("unknown api version %q (want v1 or v2)", s.APIVersion)
+	}
+
+	return nil
+}
+
+// defaultScenario builds the single anonymous scenario used when no workload
+// file was loaded, mirroring the flat CLI flags.
+func (s *Simulator) defaultScenario() Scenario {
+	return Scenario{
+		Measurements:     s.Measurements,
+		Tags:             s.Tags,
+		FieldsPerPoint:   s.FieldsPerPoint,
+		FieldSize:        s.FieldSize,
+		FieldSpecs:       s.FieldSpecs,
+		TagSize:          s.TagSize,
+		PointsPerSeries:  s.PointsPerSeries,
+		BatchSize:        s.BatchSize,
+		Delay:            s.Delay,
+		TargetMaxLatency: s.TargetMaxLatency,
+		TimeSpan:         s.TimeSpan,
+	}
+}
+
+// Run executes the program: it creates the destination database (unless
+// DryRun) and then runs every scenario, sequentially unless a scenario opts
+// into running concurrently with the rest.
+func (s *Simulator) Run(ctx context.Context) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	if !s.DryRun {
+		if err := s.createDestination(); err != nil {
+			return err
+		}
+	}
+
+	scenarios := s.Scenarios
+	if len(scenarios) == 0 {
+		scenarios = []Scenario{s.defaultScenario()}
+	}
+
+	ctx = s.withRateControllers(ctx)
+
+	prom, err := s.startPromServer()
+	if err != nil {
+		return err
+	}
+	s.prom = prom
+	defer s.prom.stop(ctx)
+
+	s.startTime = time.Now()
+
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var runErr error
+	recordErr := func(err error) {
+		errMu.Lock()
+		if runErr == nil {
+			runErr = err
+		}
+		errMu.Unlock()
+	}
+
+	for _, sc := range scenarios {
+		if sc.Concurrent {
+			wg.Add(1)
+			go func(sc Scenario) {
+				defer wg.Done()
+				if err := s.runScenario(ctx, sc); err != nil {
+					recordErr(err)
+				}
+			}(sc)
+			continue
+		}
+
+		if err := s.runScenario(ctx, sc); err != nil {
+			recordErr(err)
+		}
+	}
+	wg.Wait()
+
+	s.report(time.Since(s.startTime))
+
+	return runErr
+}
+
+// runScenario generates and writes every point for a single scenario.
+func (s *Simulator) runScenario(ctx context.Context, sc Scenario) error {
+	r := &scenarioRunner{sim: s, sc: sc}
+	return r.run(ctx)
+}
+
+// scenarioRunner holds the per-scenario state needed to generate and write
+// points; concurrently-running scenarios each get their own runner so they
+// don't share mutable delay state. Host rotation is not per-runner:
+// Simulator.nextHost round-robins across every scenario.
+type scenarioRunner struct {
+	sim *Simulator
+	sc  Scenario
+}
+
+func (r *scenarioRunner) run(ctx context.Context) error {
+	batchCh := make(chan []byte, r.sim.Concurrency)
+	go r.generateBatches(batchCh)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var writeErr error
+	var errCount int64
+
+	concurrency := r.sim.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			hist := newLatencyHistograms()
+			defer r.sim.histograms.merge(hist)
+
+			for buf := range batchCh {
+				if err := r.writeBatch(ctx, buf, hist); err != nil {
+					mu.Lock()
+					if writeErr == nil {
+						writeErr = err
+					}
+					mu.Unlock()
+					if r.sim.MaxErrors > 0 && int(atomic.AddInt64(&errCount, 1)) >= r.sim.MaxErrors {
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return writeErr
+}
+
+// generateBatches writes line-protocol batches to ch until every point in
+// the scenario has been generated.
+func (r *scenarioRunner) generateBatches(ch chan<- []byte) {
+	defer close(ch)
+
+	batchSize := r.sc.BatchSize
+	if batchSize <= 0 {
+		batchSize = r.sim.BatchSize
+	}
+
+	var buf bytes.Buffer
+	pointsInBatch := 0
+
+	for seriesN := 0; seriesN < r.seriesN(); seriesN++ {
+		for p := 0; p < r.pointsPerSeries(); p++ {
+			r.writePoint(&buf, seriesN, p)
+			pointsInBatch++
+
+			if pointsInBatch >= batchSize {
+				ch <- append([]byte(nil), buf.Bytes()...)
+				buf.Reset()
+				pointsInBatch = 0
+			}
+		}
+	}
+
+	if pointsInBatch > 0 {
+		ch <- append([]byte(nil), buf.Bytes()...)
+	}
+}
+
+func (r *scenarioRunner) measurements() int {
+	if r.sc.Measurements > 0 {
+		return r.sc.Measurements
+	}
+	return r.sim.Measurements
+}
+
+func (r *scenarioRunner) tags() []int {
+	if len(r.sc.Tags) > 0 {
+		return r.sc.Tags
+	}
+	return r.sim.Tags
+}
+
+func (r *scenarioRunner) pointsPerSeries() int {
+	if r.sc.PointsPerSeries > 0 {
+		return r.sc.PointsPerSeries
+	}
+	return r.sim.PointsPerSeries
+}
+
+func (r *scenarioRunner) fieldsPerPoint() int {
+	if r.sc.FieldsPerPoint > 0 {
+		return r.sc.FieldsPerPoint
+	}
+	return r.sim.FieldsPerPoint
+}
+
+func (r *scenarioRunner) fieldSize() int {
+	if r.sc.FieldSize > 0 {
+		return r.sc.FieldSize
+	}
+	return r.sim.FieldSize
+}
+
+func (r *scenarioRunner) tagSize() int {
+	if r.sc.TagSize != 0 {
+		return r.sc.TagSize
+	}
+	return r.sim.TagSize
+}
+
+// fieldSpecs returns the scenario's named, generator-backed fields, if any
+// were configured in place of the legacy fieldsPerPoint()/fieldSize() pair.
+// It only falls back to the simulator-wide FieldSpecs when the scenario
+// didn't itself opt into the legacy count/size fields either; otherwise a
+// scenario's own FieldsPerPoint would silently be shadowed by whatever -f
+// was used for (or defaulted to) at the top level.
+func (r *scenarioRunner) fieldSpecs() []FieldSpec {
+	if len(r.sc.FieldSpecs) > 0 {
+		return r.sc.FieldSpecs
+	}
+	if r.sc.FieldsPerPoint > 0 {
+		return nil
+	}
+	return r.sim.FieldSpecs
+}
+
+func (r *scenarioRunner) seriesN() int {
+	n := r.measurements()
+	for _, x := range r.tags() {
+		n *= x
+	}
+	return n
+}
+
+// writePoint writes a single line-protocol point for the given series/point index.
+func (r *scenarioRunner) writePoint(buf *bytes.Buffer, seriesN, pointN int) {
+	measurements := r.measurements()
+	tags := r.tags()
+
+	fmt.Fprintf(buf, "m%d", seriesN%measurements)
+	for i, card := range tags {
+		v := (seriesN / (i + 1)) % card
+		fmt.Fprintf(buf, ",tag%d=%s", i, r.pad(v, r.tagSize()))
+	}
+	buf.WriteByte(' ')
+	if specs := r.fieldSpecs(); len(specs) > 0 {
+		for i, spec := range specs {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(buf, "%s=", spec.Name)
+			buf.Write(spec.Gen.NextBatch(nil, 1))
+		}
+	} else {
+		for i := 0; i < r.fieldsPerPoint(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(buf, "f%d=%s", i, r.pad(pointN, r.fieldSize()))
+		}
+	}
+	fmt.Fprintf(buf, " %d\n", r.pointTime(pointN).UnixNano())
+}
+
+// pad formats v as a decimal string, left-padding with zeroes to width digits.
+func (r *scenarioRunner) pad(v, width int) string {
+	str := strconv.Itoa(v)
+	if width > len(str) {
+		str = strings.Repeat("0", width-len(str)) + str
+	}
+	return str
+}
+
+// pointTime returns the timestamp to use for the n-th point in a series.
+func (r *scenarioRunner) pointTime(n int) time.Time {
+	timeSpan := r.sc.TimeSpan
+	if timeSpan == 0 {
+		timeSpan = r.sim.TimeSpan
+	}
+	if timeSpan == 0 {
+		return r.sim.baseTime
+	}
+	interval := timeSpan / time.Duration(r.pointsPerSeries())
+	return r.sim.baseTime.Add(interval * time.Duration(n))
+}
+
+// nextHost returns the next host to write to, round-robin across all scenarios.
+func (s *Simulator) nextHost() string {
+	i := atomic.AddUint64(&s.hostIdx, 1)
+	return s.Hosts[int(i-1)%len(s.Hosts)]
+}
+
+// createDestination provisions wherever points will be written to: a v1
+// database (via CREATE DATABASE) or a v2 bucket (via the buckets API).
+func (s *Simulator) createDestination() error {
+	if s.APIVersion == "v2" {
+		return s.createBucket()
+	}
+	return s.createDatabase()
+}
+
+// createDatabase creates the destination database if it does not already exist.
+func (s *Simulator) createDatabase() error {
+	u, err := url.Parse(s.nextHost())
+	if err != nil {
+		return err
+	}
+	u.Path = "/query"
+
+	q := url.Values{}
+	q.Set("q", fmt.Sprintf("CREATE DATABASE %s WITH DURATION %s", s.Database, s.ShardDuration))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if s.User != "" {
+		req.SetBasicAuth(s.User, s.Password)
+	}
+
+	client := &http.Client{Timeout: time.Duration(s.DatabaseCreationTimeout) * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot create database: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot create database: received status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// createBucket creates the destination v2 bucket if it does not already
+// exist, with a retention rule derived from ShardDuration.
+func (s *Simulator) createBucket() error {
+	everySeconds, err := parseDurationSeconds(s.ShardDuration)
+	if err != nil {
+		return fmt.Errorf("cannot parse shard duration: %v", err)
+	}
+
+	body, err := json.Marshal(struct {
+		Name           string `json:"name"`
+		Org            string `json:"org"`
+		RetentionRules []struct {
+			Type         string `json:"type"`
+			EverySeconds int64  `json:"everySeconds"`
+		} `json:"retentionRules"`
+	}{
+		Name: s.Bucket,
+		// Org is the org *name* (the same -org value used as the v2 write
+		// endpoint's org= parameter). The buckets API also accepts an
+		// orgID, but that's the org's hex ID, not its name - sending the
+		// name there fails validation.
+		Org: s.Org,
+		RetentionRules: []struct {
+			Type         string `json:"type"`
+			EverySeconds int64  `json:"everySeconds"`
+		}{{Type: "expire", EverySeconds: everySeconds}},
+	})
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(s.nextHost())
+	if err != nil {
+		return err
+	}
+	u.Path = "/api/v2/buckets"
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+s.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: time.Duration(s.DatabaseCreationTimeout) * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot create bucket: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("cannot create bucket: %v", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusUnprocessableEntity:
+		// A 422 means the bucket already exists only when it names this
+		// specific conflict; any other 422 (e.g. a bad org name) is a real
+		// failure and must not be swallowed.
+		if bytes.Contains(respBody, []byte("already exists")) {
+			return nil
+		}
+		return fmt.Errorf("cannot create bucket: %s", bytes.TrimSpace(respBody))
+	default:
+		return fmt.Errorf("cannot create bucket: received status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+}
+
+// parseDurationSeconds parses a Go duration string, plus the "d"/"w" day and
+// week suffixes inch already accepts for -shard-duration, into seconds.
+func parseDurationSeconds(s string) (int64, error) {
+	if n := len(s); n > 1 {
+		switch s[n-1] {
+		case 'd':
+			days, err := strconv.ParseInt(s[:n-1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return days * 24 * 60 * 60, nil
+		case 'w':
+			weeks, err := strconv.ParseInt(s[:n-1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return weeks * 7 * 24 * 60 * 60, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return int64(d.Seconds()), nil
+}
+
+// expectedInterval estimates how long an ideal closed-loop client would
+// have spent on this batch, for coordinated-omission correction. It prefers
+// the active rate-controller target (-target-pps/-target-bps); when only a
+// target latency is set it falls back to the configured write delay, and it
+// returns 0 (no correction) when no target is set at all.
+func (r *scenarioRunner) expectedInterval(pointsN, bytesN int) time.Duration {
+	s := r.sim
+	switch {
+	case s.TargetPPS > 0:
+		return time.Duration(pointsN) * time.Second / time.Duration(s.TargetPPS)
+	case s.TargetBPS > 0:
+		return time.Duration(bytesN) * time.Second / time.Duration(s.TargetBPS)
+	case r.sc.TargetMaxLatency == 0 && s.TargetMaxLatency == 0:
+		return 0
+	}
+
+	delay := r.sc.Delay
+	if delay == 0 {
+		delay = s.Delay
+	}
+	return delay
+}
+
+// writeBatch sends a batch of line-protocol data to the next host. On
+// success it adds the batch's points to the scenario's own stats bucket and
+// records the resulting latency in the writer's histograms (hist) and the
+// prometheus metrics.
+func (r *scenarioRunner) writeBatch(ctx context.Context, buf []byte, hist *latencyHistograms) error {
+	s := r.sim
+
+	u, err := url.Parse(s.nextHost())
+	if err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	if s.APIVersion == "v2" {
+		u.Path = "/api/v2/write"
+		q.Set("org", s.Org)
+		q.Set("bucket", s.Bucket)
+		q.Set("precision", "ns")
+	} else {
+		u.Path = "/write"
+		q.Set("db", s.Database)
+		q.Set("consistency", s.Consistency)
+	}
+	u.RawQuery = q.Encode()
+
+	pointsN := bytes.Count(buf, []byte("\n"))
+	if rc, ok := ctx.Value(rateControllersKey{}).(*rateControllers); ok {
+		if rc.bps != nil {
+			if err := rc.bps.acquire(ctx, float64(len(buf))); err != nil {
+				return err
+			}
+		}
+		if rc.pps != nil {
+			if err := rc.pps.acquire(ctx, float64(pointsN)); err != nil {
+				return err
+			}
+		}
+	}
+
+	start := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	if s.APIVersion == "v2" {
+		req.Header.Set("Authorization", "Token "+s.Token)
+	} else if s.User != "" {
+		req.SetBasicAuth(s.User, s.Password)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.recordError(u.Host)
+		return fmt.Errorf("write to %s: %v", u.String(), err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	latency := time.Since(start)
+
+	if rc, ok := ctx.Value(rateControllersKey{}).(*rateControllers); ok {
+		if rc.pps != nil {
+			rc.pps.recordLatency(latency)
+		}
+		if rc.bps != nil {
+			rc.bps.recordLatency(latency)
+		}
+	}
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		s.recordError(u.Host)
+		return fmt.Errorf("write to %s returned status %d", u.String(), resp.StatusCode)
+	}
+
+	// Only a batch the server actually accepted counts toward points/bytes
+	// written, the prometheus counters and the latency histograms - a
+	// rejected batch didn't really happen as far as those totals go.
+	s.recordStats(r.sc.Name, pointsN, int64(len(buf)))
+	s.recordPromMetrics(u.Host, pointsN, len(buf), latency)
+	hist.record(latency, r.expectedInterval(pointsN, len(buf)))
+
+	delay := r.sc.Delay
+	if delay == 0 {
+		delay = s.Delay
+	}
+	targetMaxLatency := r.sc.TargetMaxLatency
+	if targetMaxLatency == 0 {
+		targetMaxLatency = s.TargetMaxLatency
+	}
+	if targetMaxLatency > 0 {
+		delay = adjustDelay(delay, latency, targetMaxLatency)
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	return nil
+}
+
+// adjustDelay nudges delay up or down by a millisecond to try to hit target.
+func adjustDelay(delay, latency, target time.Duration) time.Duration {
+	switch {
+	case latency > target && delay < time.Second:
+		return delay + time.Millisecond
+	case latency < target && delay > 0:
+		return delay - time.Millisecond
+	default:
+		return delay
+	}
+}
+
+// recordStats folds a batch's results into both the run-wide totals and the
+// named scenario's own totals, so the final report can show per-scenario
+// numbers for mixed workloads.
+func (s *Simulator) recordStats(scenario string, pointsN int, bytesN int64) {
+	s.mu.Lock()
+	s.writtenN += pointsN
+	s.totalBytes += bytesN
+	s.mu.Unlock()
+
+	s.scenarioStatsMu.Lock()
+	defer s.scenarioStatsMu.Unlock()
+	st, ok := s.scenarioStats[scenario]
+	if !ok {
+		st = &scenarioStats{}
+		s.scenarioStats[scenario] = st
+	}
+	st.writtenN += pointsN
+}
+
+// hasNamedScenariosLocked reports whether the run used more than one
+// scenario, or a single named (non-default) one - the cases where a
+// per-scenario breakdown is worth showing. Callers must hold scenarioStatsMu.
+func (s *Simulator) hasNamedScenariosLocked() bool {
+	return len(s.scenarioStats) > 1 || (len(s.scenarioStats) == 1 && s.scenarioStats[""] == nil)
+}
+
+// recordError tallies a failed write against the host that produced it, for
+// the per-host error counts in the final Results.
+func (s *Simulator) recordError(host string) {
+	s.errorsMu.Lock()
+	defer s.errorsMu.Unlock()
+	s.errorsByHost[host]++
+}
+
+// recordPromMetrics forwards a completed batch write to the live prometheus
+// metrics server, if one is running.
+func (s *Simulator) recordPromMetrics(host string, pointsN, bytesN int, latency time.Duration) {
+	s.prom.record(host, pointsN, bytesN, latency)
+}
+
+// report prints (and optionally ships) a summary of the run, broken down per
+// scenario when more than one was used or the only one was named.
+func (s *Simulator) report(elapsed time.Duration) {
+	s.mu.Lock()
+	writtenN := s.writtenN
+	s.mu.Unlock()
+
+	fmt.Fprintf(s.Stdout, "Total time: %s\n", elapsed)
+	fmt.Fprintf(s.Stdout, "Points written: %d\n", writtenN)
+
+	s.scenarioStatsMu.Lock()
+	if s.hasNamedScenariosLocked() {
+		for name, st := range s.scenarioStats {
+			fmt.Fprintf(s.Stdout, "  scenario %q: points=%d\n", name, st.writtenN)
+		}
+	}
+	s.scenarioStatsMu.Unlock()
+
+	s.histograms.mu.Lock()
+	raw, co := percentilesOf(s.histograms.raw), percentilesOf(s.histograms.co)
+	s.histograms.mu.Unlock()
+	fmt.Fprintf(s.Stdout, "Latency (raw):          p50=%.2fms p90=%.2fms p99=%.2fms p99.9=%.2fms\n",
+		raw.P50, raw.P90, raw.P99, raw.P999)
+	fmt.Fprintf(s.Stdout, "Latency (CO-corrected): p50=%.2fms p90=%.2fms p99=%.2fms p99.9=%.2fms\n",
+		co.P50, co.P90, co.P99, co.P999)
+
+	if err := s.emitResults(elapsed); err != nil {
+		fmt.Fprintf(s.Stderr, "unable to write results: %v\n", err)
+	}
+
+	if s.ReportHost == "" {
+		return
+	}
+
+	if err := s.sendReport(writtenN, elapsed); err != nil {
+		fmt.Fprintf(s.Stderr, "unable to report results: %v\n", err)
+	}
+}
+
+// sendReport sends the summary of the run to ReportHost as line-protocol:
+// one aggregate point, plus (for mixed workloads) one further point per
+// scenario, each tagged with its own "scenario" name, so downstream
+// analysis can distinguish phases instead of seeing a single blended total.
+func (s *Simulator) sendReport(writtenN int, elapsed time.Duration) error {
+	u, err := url.Parse(s.ReportHost)
+	if err != nil {
+		return err
+	}
+	u.Path = "/write"
+
+	q := url.Values{}
+	q.Set("db", "ingest_benchmarks")
+	u.RawQuery = q.Encode()
+
+	now := time.Now().UnixNano()
+
+	var baseTags bytes.Buffer
+	for k, v := range s.ReportTags {
+		fmt.Fprintf(&baseTags, ",%s=%s", k, v)
+	}
+
+	var lines bytes.Buffer
+	fmt.Fprintf(&lines, "inch%s points_written=%d,elapsed_ns=%d %d\n",
+		baseTags.String(), writtenN, elapsed.Nanoseconds(), now)
+
+	s.scenarioStatsMu.Lock()
+	if s.hasNamedScenariosLocked() {
+		for name, st := range s.scenarioStats {
+			fmt.Fprintf(&lines, "inch%s,scenario=%s points_written=%d %d\n",
+				baseTags.String(), name, st.writtenN, now)
+		}
+	}
+	s.scenarioStatsMu.Unlock()
+
+	req, err := http.NewRequest("POST", u.String(), &lines)
+	if err != nil {
+		return err
+	}
+	if s.ReportUser != "" {
+		req.SetBasicAuth(s.ReportUser, s.ReportPassword)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("report write returned status %d", resp.StatusCode)
+	}
+	return nil
+}
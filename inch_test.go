@@ -0,0 +1,163 @@
+package inch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestSimulator(host string) *Simulator {
+	s := NewSimulator()
+	s.Hosts = []string{host}
+	return s
+}
+
+func TestWriteBatchV1(t *testing.T) {
+	var gotPath, gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := newTestSimulator(srv.URL)
+	s.Database = "stress"
+	s.Consistency = "any"
+	r := &scenarioRunner{sim: s}
+
+	if err := r.writeBatch(context.Background(), []byte("m0 f0=1 1\n"), newLatencyHistograms()); err != nil {
+		t.Fatalf("writeBatch: %v", err)
+	}
+	if gotPath != "/write" {
+		t.Errorf("path = %q, want /write", gotPath)
+	}
+	if got := (gotQuery); got != "consistency=any&db=stress" {
+		t.Errorf("query = %q, want consistency=any&db=stress", got)
+	}
+}
+
+func TestWriteBatchV2(t *testing.T) {
+	var gotPath, gotQuery, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := newTestSimulator(srv.URL)
+	s.APIVersion = "v2"
+	s.Org = "myorg"
+	s.Bucket = "mybucket"
+	s.Token = "mytoken"
+	r := &scenarioRunner{sim: s}
+
+	if err := r.writeBatch(context.Background(), []byte("m0 f0=1 1\n"), newLatencyHistograms()); err != nil {
+		t.Fatalf("writeBatch: %v", err)
+	}
+	if gotPath != "/api/v2/write" {
+		t.Errorf("path = %q, want /api/v2/write", gotPath)
+	}
+	if got := (gotQuery); got != "bucket=mybucket&org=myorg&precision=ns" {
+		t.Errorf("query = %q, want bucket=mybucket&org=myorg&precision=ns", got)
+	}
+	if gotAuth != "Token mytoken" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Token mytoken")
+	}
+}
+
+func TestWriteBatchRejectedStatusNotCounted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	s := newTestSimulator(srv.URL)
+	r := &scenarioRunner{sim: s}
+
+	if err := r.writeBatch(context.Background(), []byte("m0 f0=1 1\n"), newLatencyHistograms()); err == nil {
+		t.Fatal("expected an error for a rejected batch")
+	}
+
+	s.mu.Lock()
+	writtenN := s.writtenN
+	s.mu.Unlock()
+	if writtenN != 0 {
+		t.Errorf("writtenN = %d, want 0 (rejected batch shouldn't count)", writtenN)
+	}
+}
+
+func TestCreateBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+	}{
+		{"created", http.StatusCreated, "", false},
+		{"already exists", http.StatusUnprocessableEntity, `{"message":"bucket already exists"}`, false},
+		{"other 422", http.StatusUnprocessableEntity, `{"message":"org not found"}`, true},
+		{"server error", http.StatusInternalServerError, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+					t.Fatalf("decoding request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			s := newTestSimulator(srv.URL)
+			s.APIVersion = "v2"
+			s.Org = "myorg"
+			s.Bucket = "mybucket"
+			s.Token = "mytoken"
+			s.ShardDuration = "7d"
+			s.DatabaseCreationTimeout = 5
+
+			err := s.createBucket()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if gotBody["org"] != "myorg" {
+				t.Errorf("request body org = %v, want %q", gotBody["org"], "myorg")
+			}
+			if _, hasOrgID := gotBody["orgID"]; hasOrgID {
+				t.Errorf("request body unexpectedly has orgID; bucket creation should send org (name) instead")
+			}
+		})
+	}
+}
+
+func TestParseDurationSeconds(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"7d", 7 * 24 * 60 * 60, false},
+		{"2w", 2 * 7 * 24 * 60 * 60, false},
+		{"1h", 3600, false},
+		{"bogus", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDurationSeconds(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseDurationSeconds(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parseDurationSeconds(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
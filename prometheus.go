@@ -0,0 +1,85 @@
+package inch
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// promMetrics holds the live counters/histograms exposed on PromListen while
+// a run is in progress.
+type promMetrics struct {
+	pointsWritten prometheus.Counter
+	writeLatency  *prometheus.HistogramVec
+	batchBytes    prometheus.Summary
+
+	server *http.Server
+}
+
+// startPromServer registers the inch_* metrics and serves them on
+// s.PromListen until stop is called on the returned *promMetrics. It is a no-op
+// (nil, nil) when PromListen is unset.
+func (s *Simulator) startPromServer() (*promMetrics, error) {
+	if s.PromListen == "" {
+		return nil, nil
+	}
+
+	reg := prometheus.NewRegistry()
+
+	m := &promMetrics{
+		pointsWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "inch_points_written_total",
+			Help: "Total number of points successfully written.",
+		}),
+		writeLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "inch_write_latency_seconds",
+			Help:    "Latency of write requests, by destination host.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host"}),
+		batchBytes: prometheus.NewSummary(prometheus.SummaryOpts{
+			Name:       "inch_batch_bytes",
+			Help:       "Size, in bytes, of each written batch.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}),
+	}
+	reg.MustRegister(m.pointsWritten, m.writeLatency, m.batchBytes)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	m.server = &http.Server{Addr: s.PromListen, Handler: mux}
+
+	ln, err := net.Listen("tcp", s.PromListen)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start prometheus listener: %v", err)
+	}
+
+	go m.server.Serve(ln)
+
+	return m, nil
+}
+
+// record folds one completed batch write into the live prometheus metrics.
+// m may be nil when -prom-listen was not set.
+func (m *promMetrics) record(host string, pointsN, bytesN int, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.pointsWritten.Add(float64(pointsN))
+	m.writeLatency.WithLabelValues(host).Observe(latency.Seconds())
+	m.batchBytes.Observe(float64(bytesN))
+}
+
+// stop shuts down the metrics server. m may be nil.
+func (m *promMetrics) stop(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	m.server.Shutdown(shutdownCtx)
+}
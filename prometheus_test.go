@@ -0,0 +1,46 @@
+package inch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStartPromServerNoop(t *testing.T) {
+	s := NewSimulator()
+	m, err := s.startPromServer()
+	if err != nil {
+		t.Fatalf("startPromServer: %v", err)
+	}
+	if m != nil {
+		t.Fatalf("startPromServer with no PromListen = %+v, want nil", m)
+	}
+	// Must be safe to call on a nil *promMetrics.
+	m.stop(context.Background())
+}
+
+func TestPromMetricsRecord(t *testing.T) {
+	s := NewSimulator()
+	s.PromListen = "127.0.0.1:0"
+	m, err := s.startPromServer()
+	if err != nil {
+		t.Fatalf("startPromServer: %v", err)
+	}
+	defer m.stop(context.Background())
+
+	m.record("host1", 10, 512, 5*time.Millisecond)
+	m.record("host1", 5, 256, 5*time.Millisecond)
+
+	if got := testutil.ToFloat64(m.pointsWritten); got != 15 {
+		t.Errorf("pointsWritten = %v, want 15", got)
+	}
+}
+
+func TestPromMetricsRecordNilReceiver(t *testing.T) {
+	var m *promMetrics
+	// Recording against a nil *promMetrics (the -prom-listen-unset case)
+	// must be a no-op, not a panic.
+	m.record("host1", 10, 512, 5*time.Millisecond)
+}
@@ -0,0 +1,168 @@
+package inch
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// rateControllersKey is the context.Context key under which the active
+// rate controllers (if any) are stored. Scenarios read it back out in
+// writeBatch so every concurrent writer shares the same token bucket.
+type rateControllersKey struct{}
+
+// rateControllers bundles the optional points/sec and bytes/sec controllers
+// for a run. Either, both, or neither may be set.
+type rateControllers struct {
+	pps *rateController
+	bps *rateController
+}
+
+// withRateControllers returns ctx annotated with the configured rate
+// controllers, or ctx unchanged if neither -target-pps nor -target-bps was set.
+func (s *Simulator) withRateControllers(ctx context.Context) context.Context {
+	if s.TargetPPS <= 0 && s.TargetBPS <= 0 {
+		return ctx
+	}
+
+	rc := &rateControllers{}
+	if s.TargetPPS > 0 {
+		rc.pps = newRateController(float64(s.TargetPPS), s.TargetMaxLatency)
+	}
+	if s.TargetBPS > 0 {
+		rc.bps = newRateController(float64(s.TargetBPS), s.TargetMaxLatency)
+	}
+	return context.WithValue(ctx, rateControllersKey{}, rc)
+}
+
+// rateController is a simple token bucket: tokens refill continuously at
+// `rate` tokens/sec, up to `capacity`, which starts at 2*rate/100 (two
+// refill intervals' worth, at a 10ms refill granularity) but grows to fit
+// the largest single acquire() call made against it — otherwise a batch (or
+// byte count) bigger than that initial capacity could never be satisfied
+// and acquire would block forever. When a -target-latency is also
+// configured, recordLatency nudges `rate`: it raises it by 10% of its
+// current value once per successful second (a multiplicative step, not an
+// additive one), and cuts it by 0.8x as soon as the p99 latency over the
+// trailing 2s exceeds the target.
+type rateController struct {
+	mu           sync.Mutex
+	rate         float64
+	capacity     float64
+	tokens       float64
+	lastRefill   time.Time
+	lastIncrease time.Time
+	target       time.Duration
+	samples      []latencySample
+}
+
+type latencySample struct {
+	at time.Time
+	d  time.Duration
+}
+
+func newRateController(rate float64, target time.Duration) *rateController {
+	now := time.Now()
+	capacity := 2 * rate / 100
+	return &rateController{
+		rate:         rate,
+		capacity:     capacity,
+		tokens:       capacity,
+		lastRefill:   now,
+		lastIncrease: now,
+		target:       target,
+	}
+}
+
+// acquire blocks, refilling every 10ms, until n tokens are available or ctx
+// is canceled. n may exceed the bucket's current capacity (e.g. a batch
+// bigger than two refill intervals' worth of tokens); acquire grows the
+// bucket to fit rather than blocking on a ceiling it can never reach.
+func (rc *rateController) acquire(ctx context.Context, n float64) error {
+	for {
+		rc.mu.Lock()
+		if n > rc.capacity {
+			rc.capacity = n
+		}
+		rc.refillLocked(time.Now())
+		if rc.tokens >= n {
+			rc.tokens -= n
+			rc.mu.Unlock()
+			return nil
+		}
+		rc.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (rc *rateController) refillLocked(now time.Time) {
+	elapsed := now.Sub(rc.lastRefill).Seconds()
+	rc.lastRefill = now
+
+	rc.tokens += elapsed * rc.rate
+	if rc.tokens > rc.capacity {
+		rc.tokens = rc.capacity
+	}
+}
+
+// recordLatency folds a completed write's latency into the controller's
+// AIMD decision. It is a no-op when no -target-latency was configured.
+func (rc *rateController) recordLatency(d time.Duration) {
+	if rc.target == 0 {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := time.Now()
+	rc.samples = append(rc.samples, latencySample{at: now, d: d})
+	cutoff := now.Add(-2 * time.Second)
+	i := 0
+	for i < len(rc.samples) && rc.samples[i].at.Before(cutoff) {
+		i++
+	}
+	rc.samples = rc.samples[i:]
+
+	if p99 := percentileOf(rc.samples, 0.99); p99 > rc.target {
+		rc.rate *= 0.8
+		rc.capacity = 2 * rc.rate / 100
+		if rc.tokens > rc.capacity {
+			rc.tokens = rc.capacity
+		}
+		rc.lastIncrease = now
+		return
+	}
+
+	if now.Sub(rc.lastIncrease) >= time.Second {
+		rc.rate += rc.rate * 0.1
+		rc.capacity = 2 * rc.rate / 100
+		rc.lastIncrease = now
+	}
+}
+
+// percentileOf returns the p-th percentile (0 < p <= 1) latency among samples.
+func percentileOf(samples []latencySample, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	d := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		d[i] = s.d
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	idx := int(p*float64(len(d))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(d) {
+		idx = len(d) - 1
+	}
+	return d[idx]
+}
@@ -0,0 +1,31 @@
+package inch
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateControllerAcquire(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		n    float64
+	}{
+		{"smaller than initial capacity", 500, 5},
+		{"larger batch than initial capacity", 100000, 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newRateController(tt.rate, 0)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			if err := rc.acquire(ctx, tt.n); err != nil {
+				t.Fatalf("acquire(%v) did not complete: %v", tt.n, err)
+			}
+		})
+	}
+}
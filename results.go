@@ -0,0 +1,176 @@
+package inch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	hdr "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// LatencyPercentiles is a handful of commonly-plotted points off a latency
+// histogram, in milliseconds.
+type LatencyPercentiles struct {
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P99  float64 `json:"p99_ms"`
+	P999 float64 `json:"p999_ms"`
+}
+
+func percentilesOf(h *hdr.Histogram) LatencyPercentiles {
+	return LatencyPercentiles{
+		P50:  microsToMs(h.ValueAtQuantile(50)),
+		P90:  microsToMs(h.ValueAtQuantile(90)),
+		P99:  microsToMs(h.ValueAtQuantile(99)),
+		P999: microsToMs(h.ValueAtQuantile(99.9)),
+	}
+}
+
+func microsToMs(v int64) float64 {
+	return float64(v) / 1000
+}
+
+// Results is the final, machine-readable summary of a run: produced once
+// Run has finished and written out in whatever format -results-format asks
+// for.
+type Results struct {
+	TotalPoints int     `json:"total_points"`
+	TotalBytes  int64   `json:"total_bytes"`
+	ElapsedSec  float64 `json:"elapsed_seconds"`
+
+	// Latency is measured twice: Raw is what writers actually observed;
+	// CoCorrected additionally synthesizes the samples a coordinated-
+	// omission-corrected client would have seen (see hdr.go). They are
+	// equal when no -target-pps/-target-bps/-target-latency was set.
+	Raw         LatencyPercentiles `json:"latency_raw"`
+	CoCorrected LatencyPercentiles `json:"latency_co_corrected"`
+
+	// RawHistogram/CoCorrectedHistogram are the full HDR bucket dumps,
+	// for tools (e.g. hdr-plot) that want more than the percentiles above.
+	RawHistogram         *hdr.Snapshot `json:"raw_histogram,omitempty"`
+	CoCorrectedHistogram *hdr.Snapshot `json:"co_corrected_histogram,omitempty"`
+
+	ErrorsByHost map[string]int `json:"errors_by_host"`
+	// RetryCount is always 0 today: inch does not currently retry failed
+	// writes. The field exists so downstream tooling has a stable place
+	// to read it from once retries are added.
+	RetryCount int `json:"retry_count"`
+}
+
+// buildResults summarizes the simulator's accumulated stats into a Results value.
+func (s *Simulator) buildResults(elapsed time.Duration) Results {
+	s.mu.Lock()
+	writtenN := s.writtenN
+	totalBytes := s.totalBytes
+	retryCount := s.retryCount
+	s.mu.Unlock()
+
+	s.errorsMu.Lock()
+	errorsByHost := make(map[string]int, len(s.errorsByHost))
+	for k, v := range s.errorsByHost {
+		errorsByHost[k] = v
+	}
+	s.errorsMu.Unlock()
+
+	s.histograms.mu.Lock()
+	rawHist, coHist := s.histograms.raw, s.histograms.co
+	s.histograms.mu.Unlock()
+
+	return Results{
+		TotalPoints:          writtenN,
+		TotalBytes:           totalBytes,
+		ElapsedSec:           elapsed.Seconds(),
+		Raw:                  percentilesOf(rawHist),
+		CoCorrected:          percentilesOf(coHist),
+		RawHistogram:         rawHist.Export(),
+		CoCorrectedHistogram: coHist.Export(),
+		ErrorsByHost:         errorsByHost,
+		RetryCount:           retryCount,
+	}
+}
+
+// writeResults renders r in the requested format ("json", "csv", or
+// "influx") to w.
+func writeResults(w io.Writer, format string, r Results) error {
+	switch format {
+	case "", "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{
+			"total_points", "total_bytes", "elapsed_seconds",
+			"latency_raw_p50_ms", "latency_raw_p90_ms", "latency_raw_p99_ms", "latency_raw_p999_ms",
+			"latency_co_p50_ms", "latency_co_p90_ms", "latency_co_p99_ms", "latency_co_p999_ms",
+			"retry_count",
+		}
+		row := []string{
+			strconv.Itoa(r.TotalPoints),
+			strconv.FormatInt(r.TotalBytes, 10),
+			strconv.FormatFloat(r.ElapsedSec, 'f', -1, 64),
+			strconv.FormatFloat(r.Raw.P50, 'f', -1, 64),
+			strconv.FormatFloat(r.Raw.P90, 'f', -1, 64),
+			strconv.FormatFloat(r.Raw.P99, 'f', -1, 64),
+			strconv.FormatFloat(r.Raw.P999, 'f', -1, 64),
+			strconv.FormatFloat(r.CoCorrected.P50, 'f', -1, 64),
+			strconv.FormatFloat(r.CoCorrected.P90, 'f', -1, 64),
+			strconv.FormatFloat(r.CoCorrected.P99, 'f', -1, 64),
+			strconv.FormatFloat(r.CoCorrected.P999, 'f', -1, 64),
+			strconv.Itoa(r.RetryCount),
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+		for host, n := range r.ErrorsByHost {
+			if err := cw.Write([]string{"errors", host, strconv.Itoa(n)}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "influx":
+		_, err := fmt.Fprintf(w,
+			"inch_results points_written=%d,bytes_written=%d,elapsed_seconds=%f,"+
+				"latency_raw_p50_ms=%f,latency_raw_p90_ms=%f,latency_raw_p99_ms=%f,latency_raw_p999_ms=%f,"+
+				"latency_co_p50_ms=%f,latency_co_p90_ms=%f,latency_co_p99_ms=%f,latency_co_p999_ms=%f,"+
+				"retry_count=%d %d\n",
+			r.TotalPoints, r.TotalBytes, r.ElapsedSec,
+			r.Raw.P50, r.Raw.P90, r.Raw.P99, r.Raw.P999,
+			r.CoCorrected.P50, r.CoCorrected.P90, r.CoCorrected.P99, r.CoCorrected.P999,
+			r.RetryCount,
+			time.Now().UnixNano())
+		return err
+	default:
+		return fmt.Errorf("unknown results format %q (want json, csv or influx)", format)
+	}
+}
+
+// emitResults writes the run's Results to ResultsFile (or Stdout, if unset)
+// in ResultsFormat.
+func (s *Simulator) emitResults(elapsed time.Duration) error {
+	if s.ResultsFormat == "" && s.ResultsFile == "" {
+		return nil
+	}
+
+	r := s.buildResults(elapsed)
+
+	out := s.Stdout
+	if s.ResultsFile != "" {
+		f, err := os.Create(s.ResultsFile)
+		if err != nil {
+			return fmt.Errorf("cannot create results file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return writeResults(out, s.ResultsFormat, r)
+}
@@ -0,0 +1,99 @@
+package inch
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleResults() Results {
+	return Results{
+		TotalPoints: 100,
+		TotalBytes:  2048,
+		ElapsedSec:  1.5,
+		Raw:         LatencyPercentiles{P50: 1, P90: 2, P99: 3, P999: 4},
+		CoCorrected: LatencyPercentiles{P50: 5, P90: 6, P99: 7, P999: 8},
+		ErrorsByHost: map[string]int{
+			"http://host1:8086": 2,
+		},
+		RetryCount: 0,
+	}
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "json", sampleResults()); err != nil {
+		t.Fatalf("writeResults: %v", err)
+	}
+
+	var got Results
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshaling output: %v", err)
+	}
+	if got.TotalPoints != 100 || got.TotalBytes != 2048 {
+		t.Errorf("got %+v, want total_points=100 total_bytes=2048", got)
+	}
+}
+
+func TestWriteResultsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "csv", sampleResults()); err != nil {
+		t.Fatalf("writeResults: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "total_points") {
+		t.Errorf("csv output missing header: %q", out)
+	}
+	if !strings.Contains(out, "100") {
+		t.Errorf("csv output missing total_points value: %q", out)
+	}
+	if !strings.Contains(out, "errors,http://host1:8086,2") {
+		t.Errorf("csv output missing per-host error row: %q", out)
+	}
+}
+
+func TestWriteResultsInflux(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "influx", sampleResults()); err != nil {
+		t.Fatalf("writeResults: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "inch_results ") {
+		t.Errorf("influx output = %q, want it to start with the measurement name", out)
+	}
+	if !strings.Contains(out, "points_written=100") {
+		t.Errorf("influx output missing points_written: %q", out)
+	}
+}
+
+func TestWriteResultsUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResults(&buf, "xml", sampleResults()); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestBuildResults(t *testing.T) {
+	s := NewSimulator()
+	s.writtenN = 42
+	s.totalBytes = 4096
+	s.errorsByHost["http://host1:8086"] = 1
+
+	r := s.buildResults(2 * time.Second)
+	if r.TotalPoints != 42 {
+		t.Errorf("TotalPoints = %d, want 42", r.TotalPoints)
+	}
+	if r.TotalBytes != 4096 {
+		t.Errorf("TotalBytes = %d, want 4096", r.TotalBytes)
+	}
+	if r.ElapsedSec != 2 {
+		t.Errorf("ElapsedSec = %v, want 2", r.ElapsedSec)
+	}
+	if r.ErrorsByHost["http://host1:8086"] != 1 {
+		t.Errorf("ErrorsByHost = %v, want host1: 1", r.ErrorsByHost)
+	}
+}